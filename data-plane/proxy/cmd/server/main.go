@@ -27,18 +27,35 @@ func main() {
 		Str("port", cfg.Port).
 		Int("batch_size", cfg.BatchSize).
 		Dur("batch_timeout", cfg.BatchTimeout).
-		Str("model_server", cfg.ModelServerURL).
+		Strs("model_servers", cfg.ModelServerURLs).
+		Str("backend_strategy", cfg.BackendStrategy).
 		Msg("Starting NexusML Inference Proxy")
 
-	// Create model client
-	modelClient := client.New(cfg.ModelServerURL)
-
-	// Create batcher with the model client's ProcessBatch function
-	b := batcher.New(cfg.BatchSize, cfg.BatchTimeout, modelClient.ProcessBatch)
+	// Create the backend group that fans batches out across model servers
+	backends := client.NewBackendGroup(
+		"default",
+		cfg.ModelServerURLs,
+		client.Strategy(cfg.BackendStrategy),
+		cfg.BackendMaxFailures,
+		cfg.BackendBaseBackoff,
+		cfg.BackendMaxBackoff,
+	)
+
+	// Create batcher with the backend group's ProcessBatch function
+	b := batcher.New(cfg.BatchSize, cfg.BatchTimeout, backends.ProcessBatch)
+	b.SetStreamProcessFunc(backends.ProcessStream)
+	if cfg.AdaptiveBatching {
+		b.EnableAdaptive(cfg.BatchSizeMin, cfg.BatchSizeMax, cfg.BatchLatencySLO)
+		log.Info().
+			Int("batch_size_min", cfg.BatchSizeMin).
+			Int("batch_size_max", cfg.BatchSizeMax).
+			Dur("batch_latency_slo", cfg.BatchLatencySLO).
+			Msg("Adaptive batching enabled")
+	}
 	b.Start()
 
 	// Create router
-	r := router.New(b)
+	r := router.New(b, backends, cfg)
 
 	// Create HTTP server
 	server := &http.Server{
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -15,8 +16,22 @@ type Config struct {
 	BatchSize    int
 	BatchTimeout time.Duration
 
+	// Adaptive batching settings (disabled by default for backwards compatibility)
+	AdaptiveBatching bool
+	BatchSizeMin     int
+	BatchSizeMax     int
+	BatchLatencySLO  time.Duration
+
 	// Backend model server settings
-	ModelServerURL string
+	ModelServerURLs    []string
+	BackendStrategy    string
+	BackendMaxFailures int
+	BackendBaseBackoff time.Duration
+	BackendMaxBackoff  time.Duration
+
+	// Batch endpoint limits
+	MaxBatchRequestItems  int
+	MaxBatchResponseBytes int
 
 	// Logging
 	LogLevel string
@@ -25,11 +40,23 @@ type Config struct {
 // Load reads configuration from environment variables with sensible defaults
 func Load() *Config {
 	return &Config{
-		Port:           getEnv("PORT", "8080"),
-		BatchSize:      getEnvInt("BATCH_SIZE", 32),
-		BatchTimeout:   getEnvDuration("BATCH_TIMEOUT_MS", 50),
-		ModelServerURL: getEnv("MODEL_SERVER_URL", "http://localhost:8000"),
-		LogLevel:       getEnv("LOG_LEVEL", "info"),
+		Port:                  getEnv("PORT", "8080"),
+		BatchSize:             getEnvInt("BATCH_SIZE", 32),
+		BatchTimeout:          getEnvDuration("BATCH_TIMEOUT_MS", 50),
+		AdaptiveBatching:      getEnvBool("ADAPTIVE_BATCHING", false),
+		BatchSizeMin:          getEnvInt("BATCH_SIZE_MIN", 1),
+		BatchSizeMax:          getEnvInt("BATCH_SIZE_MAX", 256),
+		BatchLatencySLO:       getEnvDuration("BATCH_LATENCY_SLO_MS", 100),
+		// Entries may carry a weight for BACKEND_STRATEGY=weighted via a
+		// "|<weight>" suffix, e.g. "http://host-a:8000|3,http://host-b:8000".
+		ModelServerURLs:       getEnvStringList("MODEL_SERVER_URLS", []string{"http://localhost:8000"}),
+		BackendStrategy:       getEnv("BACKEND_STRATEGY", "round_robin"),
+		BackendMaxFailures:    getEnvInt("BACKEND_MAX_CONSECUTIVE_FAILURES", 3),
+		BackendBaseBackoff:    getEnvDuration("BACKEND_BASE_BACKOFF_MS", 1000),
+		BackendMaxBackoff:     getEnvDuration("BACKEND_MAX_BACKOFF_MS", 30000),
+		MaxBatchRequestItems:  getEnvInt("MAX_BATCH_REQUEST_ITEMS", 1000),
+		MaxBatchResponseBytes: getEnvInt("MAX_BATCH_RESPONSE_BYTES", 25*1024*1024),
+		LogLevel:              getEnv("LOG_LEVEL", "info"),
 	}
 }
 
@@ -53,3 +80,33 @@ func getEnvDuration(key string, defaultMs int) time.Duration {
 	ms := getEnvInt(key, defaultMs)
 	return time.Duration(ms) * time.Millisecond
 }
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolVal, err := strconv.ParseBool(value); err == nil {
+			return boolVal
+		}
+	}
+	return defaultValue
+}
+
+// getEnvStringList reads a comma-separated list from the environment,
+// trimming whitespace around each entry.
+func getEnvStringList(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	parts := strings.Split(value, ",")
+	list := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			list = append(list, p)
+		}
+	}
+	if len(list) == 0 {
+		return defaultValue
+	}
+	return list
+}
@@ -10,16 +10,31 @@ import (
 
 // Request represents a single inference request waiting to be batched
 type Request struct {
-	ID       string
-	Payload  []byte
-	Response chan Response
+	ID        string
+	Payload   []byte
+	Streaming bool
+	Response  chan Response
 }
 
-// Response represents the result of an inference request
+// Response represents the result of an inference request. For a streaming
+// request, Chunks is set instead of Data and carries incremental results.
 type Response struct {
-	ID    string
-	Data  []byte
-	Error error
+	ID       string
+	Data     []byte
+	ServedBy string
+	Chunks   chan StreamChunk
+	Error    error
+}
+
+// StreamChunk is one incremental piece of a streaming response. ServedBy is
+// set on every chunk read from a backend's stream so callers can log or
+// report it the same way Response does for non-streaming requests, without
+// waiting for the stream to finish. Error chunks for a stream that could not
+// be started or ended early may leave ServedBy empty.
+type StreamChunk struct {
+	Data     []byte
+	ServedBy string
+	Err      error
 }
 
 // Batch represents a collection of requests to be processed together
@@ -30,21 +45,46 @@ type Batch struct {
 // ProcessFunc is the function signature for processing a batch of requests
 type ProcessFunc func(ctx context.Context, batch *Batch) []Response
 
+// ProcessStreamFunc handles a batch of streaming requests. It must write
+// each request's incremental results to its entry in chunkChans (keyed by
+// request ID) and close that channel once the request is complete.
+type ProcessStreamFunc func(ctx context.Context, batch *Batch, chunkChans map[string]chan StreamChunk)
+
+// Adaptive batching tuning constants.
+const (
+	adaptiveEWMAAlpha         = 0.2
+	adaptiveGoodBatchesToGrow = 5
+	adaptiveTimeoutStep       = 5 * time.Millisecond
+	adaptiveMinTimeout        = time.Millisecond
+	adaptiveMaxTimeout        = time.Second
+	adaptiveMinBatchSize      = 1
+)
+
 // Batcher collects incoming requests and processes them in batches
 type Batcher struct {
-	maxBatchSize int
-	timeout      time.Duration
-	processFunc  ProcessFunc
+	maxBatchSize      int
+	timeout           time.Duration
+	processFunc       ProcessFunc
+	streamProcessFunc ProcessStreamFunc
 
 	requestChan chan *Request
 	stopChan    chan struct{}
 	wg          sync.WaitGroup
 
 	// Metrics
-	mu              sync.RWMutex
-	totalRequests   int64
-	totalBatches    int64
-	avgBatchSize    float64
+	mu            sync.RWMutex
+	totalRequests int64
+	totalBatches  int64
+	avgBatchSize  float64
+
+	// Adaptive batching state, guarded by mu. Disabled by default, in which
+	// case maxBatchSize/timeout behave exactly as before.
+	adaptiveEnabled   bool
+	minBatchSize      int
+	maxBatchSizeBound int
+	latencySLO        time.Duration
+	ewmaLatency       float64
+	goodBatches       int
 }
 
 // New creates a new Batcher with the given configuration
@@ -58,6 +98,30 @@ func New(maxBatchSize int, timeout time.Duration, processFunc ProcessFunc) *Batc
 	}
 }
 
+// SetStreamProcessFunc registers the function used to process batches
+// submitted via SubmitStreaming. Streaming requests submitted before a
+// stream process function is set fail with ErrStreamingNotSupported.
+func (b *Batcher) SetStreamProcessFunc(f ProcessStreamFunc) {
+	b.streamProcessFunc = f
+}
+
+// EnableAdaptive turns on adaptive batch sizing: batch size is tuned between
+// minBatchSize and maxBatchSize based on measured downstream latency against
+// latencySLO, instead of staying fixed at the value passed to New.
+func (b *Batcher) EnableAdaptive(minBatchSize, maxBatchSize int, latencySLO time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if minBatchSize < adaptiveMinBatchSize {
+		minBatchSize = adaptiveMinBatchSize
+	}
+
+	b.adaptiveEnabled = true
+	b.minBatchSize = minBatchSize
+	b.maxBatchSizeBound = maxBatchSize
+	b.latencySLO = latencySLO
+}
+
 // Start begins the batching goroutine
 func (b *Batcher) Start() {
 	b.wg.Add(1)
@@ -75,14 +139,42 @@ func (b *Batcher) Stop() {
 	log.Info().Msg("Batcher stopped")
 }
 
-// Submit adds a request to the batch queue and waits for the response
-func (b *Batcher) Submit(ctx context.Context, id string, payload []byte) ([]byte, error) {
+// Submit adds a request to the batch queue and waits for the response.
+// It returns the response data, the identifier of the backend that served
+// the request, and any error.
+func (b *Batcher) Submit(ctx context.Context, id string, payload []byte) ([]byte, string, error) {
 	req := &Request{
 		ID:       id,
 		Payload:  payload,
 		Response: make(chan Response, 1),
 	}
 
+	select {
+	case b.requestChan <- req:
+		// Request submitted
+	case <-ctx.Done():
+		return nil, "", ctx.Err()
+	}
+
+	select {
+	case resp := <-req.Response:
+		return resp.Data, resp.ServedBy, resp.Error
+	case <-ctx.Done():
+		return nil, "", ctx.Err()
+	}
+}
+
+// SubmitStreaming adds a streaming request to the batch queue and returns a
+// channel of incremental chunks once the batch containing it is dispatched
+// for processing. The channel is closed when the stream completes.
+func (b *Batcher) SubmitStreaming(ctx context.Context, id string, payload []byte) (<-chan StreamChunk, error) {
+	req := &Request{
+		ID:        id,
+		Payload:   payload,
+		Streaming: true,
+		Response:  make(chan Response, 1),
+	}
+
 	select {
 	case b.requestChan <- req:
 		// Request submitted
@@ -92,7 +184,10 @@ func (b *Batcher) Submit(ctx context.Context, id string, payload []byte) ([]byte
 
 	select {
 	case resp := <-req.Response:
-		return resp.Data, resp.Error
+		if resp.Error != nil {
+			return nil, resp.Error
+		}
+		return resp.Chunks, nil
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	}
@@ -116,8 +211,10 @@ func (b *Batcher) batchLoop() {
 
 // collectBatch collects requests until batch is full or timeout expires
 func (b *Batcher) collectBatch() *Batch {
+	maxBatchSize, timeout := b.currentLimits()
+
 	batch := &Batch{
-		Requests: make([]*Request, 0, b.maxBatchSize),
+		Requests: make([]*Request, 0, maxBatchSize),
 	}
 
 	// Wait for first request or stop signal
@@ -129,11 +226,11 @@ func (b *Batcher) collectBatch() *Batch {
 	}
 
 	// Set timeout for collecting more requests
-	timer := time.NewTimer(b.timeout)
+	timer := time.NewTimer(timeout)
 	defer timer.Stop()
 
 	// Collect more requests until batch is full or timeout
-	for len(batch.Requests) < b.maxBatchSize {
+	for len(batch.Requests) < maxBatchSize {
 		select {
 		case req := <-b.requestChan:
 			batch.Requests = append(batch.Requests, req)
@@ -149,16 +246,48 @@ func (b *Batcher) collectBatch() *Batch {
 	return batch
 }
 
-// processBatch sends the batch to the process function and routes responses
+// currentLimits returns the batch size and timeout to use for the next
+// batch, which adaptive batching may have tuned since the last call.
+func (b *Batcher) currentLimits() (int, time.Duration) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.maxBatchSize, b.timeout
+}
+
+// processBatch splits the batch into streaming and unary requests and
+// dispatches each to the appropriate process function.
 func (b *Batcher) processBatch(batch *Batch) {
+	var streamReqs, unaryReqs []*Request
+	for _, req := range batch.Requests {
+		if req.Streaming {
+			streamReqs = append(streamReqs, req)
+		} else {
+			unaryReqs = append(unaryReqs, req)
+		}
+	}
+
+	if len(streamReqs) > 0 {
+		b.dispatchStreamBatch(streamReqs)
+	}
+
+	if len(unaryReqs) > 0 {
+		b.processUnaryBatch(unaryReqs)
+	}
+}
+
+// processUnaryBatch sends a batch of non-streaming requests to the process
+// function and routes the responses back to their callers.
+func (b *Batcher) processUnaryBatch(reqs []*Request) {
 	ctx := context.Background()
 
 	log.Debug().
-		Int("batch_size", len(batch.Requests)).
+		Int("batch_size", len(reqs)).
 		Msg("Processing batch")
 
-	// Call the process function
-	responses := b.processFunc(ctx, batch)
+	// Call the process function, timing it for adaptive batch sizing
+	start := time.Now()
+	responses := b.processFunc(ctx, &Batch{Requests: reqs})
+	duration := time.Since(start)
 
 	// Route responses back to waiting requests
 	responseMap := make(map[string]Response)
@@ -166,7 +295,7 @@ func (b *Batcher) processBatch(batch *Batch) {
 		responseMap[resp.ID] = resp
 	}
 
-	for _, req := range batch.Requests {
+	for _, req := range reqs {
 		if resp, ok := responseMap[req.ID]; ok {
 			req.Response <- resp
 		} else {
@@ -179,7 +308,102 @@ func (b *Batcher) processBatch(batch *Batch) {
 	}
 
 	// Update metrics
-	b.updateMetrics(len(batch.Requests))
+	b.updateMetrics(len(reqs))
+	b.recordLatency(duration)
+}
+
+// dispatchStreamBatch hands each request a chunk channel and kicks off the
+// stream process function asynchronously, since a stream can stay open far
+// longer than a normal batch processing call.
+func (b *Batcher) dispatchStreamBatch(reqs []*Request) {
+	if b.streamProcessFunc == nil {
+		for _, req := range reqs {
+			req.Response <- Response{ID: req.ID, Error: ErrStreamingNotSupported}
+			close(req.Response)
+		}
+		return
+	}
+
+	chunkChans := make(map[string]chan StreamChunk, len(reqs))
+	for _, req := range reqs {
+		ch := make(chan StreamChunk, 16)
+		chunkChans[req.ID] = ch
+		req.Response <- Response{ID: req.ID, Chunks: ch}
+		close(req.Response)
+	}
+
+	log.Debug().
+		Int("batch_size", len(reqs)).
+		Msg("Processing streaming batch")
+
+	go b.streamProcessFunc(context.Background(), &Batch{Requests: reqs}, chunkChans)
+}
+
+// recordLatency feeds the observed batch processing duration into an EWMA
+// used as a lightweight proxy for p95 latency, and, when adaptive batching
+// is enabled, grows or shrinks the batch size/timeout in response.
+func (b *Batcher) recordLatency(d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.ewmaLatency == 0 {
+		b.ewmaLatency = d.Seconds()
+	} else {
+		b.ewmaLatency = adaptiveEWMAAlpha*d.Seconds() + (1-adaptiveEWMAAlpha)*b.ewmaLatency
+	}
+
+	if !b.adaptiveEnabled {
+		return
+	}
+
+	backlogNonTrivial := len(b.requestChan) > 0
+
+	if b.ewmaLatency <= b.latencySLO.Seconds() {
+		b.goodBatches++
+		if b.goodBatches >= adaptiveGoodBatchesToGrow && backlogNonTrivial {
+			b.growLocked()
+			b.goodBatches = 0
+		}
+		return
+	}
+
+	b.shrinkLocked()
+	b.goodBatches = 0
+}
+
+// growLocked increases batch size, or shrinks the timeout once batch size
+// is already at its bound. Callers must hold mu.
+func (b *Batcher) growLocked() {
+	if b.maxBatchSize < b.maxBatchSizeBound {
+		b.maxBatchSize++
+		return
+	}
+
+	b.timeout -= adaptiveTimeoutStep
+	if b.timeout < adaptiveMinTimeout {
+		b.timeout = adaptiveMinTimeout
+	}
+}
+
+// shrinkLocked decreases batch size, or grows the timeout once batch size
+// is already at its bound. The batch size never drops below
+// adaptiveMinBatchSize, regardless of how BATCH_SIZE_MIN is configured.
+// Callers must hold mu.
+func (b *Batcher) shrinkLocked() {
+	floor := b.minBatchSize
+	if floor < adaptiveMinBatchSize {
+		floor = adaptiveMinBatchSize
+	}
+
+	if b.maxBatchSize > floor {
+		b.maxBatchSize--
+		return
+	}
+
+	b.timeout += adaptiveTimeoutStep
+	if b.timeout > adaptiveMaxTimeout {
+		b.timeout = adaptiveMaxTimeout
+	}
 }
 
 func (b *Batcher) updateMetrics(batchSize int) {
@@ -198,9 +422,19 @@ func (b *Batcher) Metrics() (totalRequests, totalBatches int64, avgBatchSize flo
 	return b.totalRequests, b.totalBatches, b.avgBatchSize
 }
 
+// AdaptiveMetrics returns the batch size and timeout currently in effect,
+// along with the EWMA latency adaptive batching is tuning against.
+func (b *Batcher) AdaptiveMetrics() (currentBatchSize int, currentTimeout time.Duration, latencySeconds float64) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.maxBatchSize, b.timeout, b.ewmaLatency
+}
+
 // Custom errors
 type BatcherError string
 
 func (e BatcherError) Error() string { return string(e) }
 
 const ErrResponseNotFound = BatcherError("response not found for request")
+
+const ErrStreamingNotSupported = BatcherError("streaming is not supported by this batcher")
@@ -0,0 +1,108 @@
+package batcher
+
+import (
+	"testing"
+	"time"
+)
+
+func TestShrinkLocked_NeverBelowHardFloor(t *testing.T) {
+	b := New(4, 10*time.Millisecond, nil)
+	b.minBatchSize = 0 // misconfiguration: BATCH_SIZE_MIN below the hard floor
+
+	b.mu.Lock()
+	for i := 0; i < 10; i++ {
+		b.shrinkLocked()
+	}
+	size := b.maxBatchSize
+	b.mu.Unlock()
+
+	if size < adaptiveMinBatchSize {
+		t.Fatalf("batch size shrank to %d, want >= %d", size, adaptiveMinBatchSize)
+	}
+}
+
+func TestShrinkLocked_GrowsTimeoutOnceAtFloor(t *testing.T) {
+	b := New(1, 500*time.Millisecond, nil)
+	b.minBatchSize = 1
+
+	b.mu.Lock()
+	b.shrinkLocked()
+	timeout := b.timeout
+	b.mu.Unlock()
+
+	if timeout <= 500*time.Millisecond {
+		t.Fatalf("timeout = %v, want it to grow past the starting 500ms once batch size is at its floor", timeout)
+	}
+}
+
+func TestGrowLocked_GrowsBatchSizeUpToBound(t *testing.T) {
+	b := New(4, 10*time.Millisecond, nil)
+	b.maxBatchSizeBound = 8
+
+	b.mu.Lock()
+	b.growLocked()
+	size := b.maxBatchSize
+	b.mu.Unlock()
+
+	if size != 5 {
+		t.Fatalf("batch size = %d, want 5", size)
+	}
+}
+
+func TestGrowLocked_ShrinksTimeoutOnceAtBound(t *testing.T) {
+	b := New(8, 20*time.Millisecond, nil)
+	b.maxBatchSizeBound = 8
+
+	b.mu.Lock()
+	b.growLocked()
+	size, timeout := b.maxBatchSize, b.timeout
+	b.mu.Unlock()
+
+	if size != 8 {
+		t.Fatalf("batch size = %d, want it to stay at the bound of 8", size)
+	}
+	if timeout != 20*time.Millisecond-adaptiveTimeoutStep {
+		t.Fatalf("timeout = %v, want it to shrink by one step", timeout)
+	}
+}
+
+func TestRecordLatency_GrowsAfterConsecutiveGoodBatchesWithBacklog(t *testing.T) {
+	b := New(4, 10*time.Millisecond, nil)
+	b.adaptiveEnabled = true
+	b.minBatchSize = 1
+	b.maxBatchSizeBound = 16
+	b.latencySLO = 100 * time.Millisecond
+
+	// Non-empty backlog is required for recordLatency to act on good batches.
+	b.requestChan <- &Request{ID: "backlogged"}
+
+	for i := 0; i < adaptiveGoodBatchesToGrow; i++ {
+		b.recordLatency(10 * time.Millisecond)
+	}
+
+	b.mu.RLock()
+	size := b.maxBatchSize
+	b.mu.RUnlock()
+
+	if size != 5 {
+		t.Fatalf("batch size = %d, want 5 after %d good batches with a backlog", size, adaptiveGoodBatchesToGrow)
+	}
+}
+
+func TestRecordLatency_ShrinksOnSLOBreach(t *testing.T) {
+	b := New(4, 10*time.Millisecond, nil)
+	b.adaptiveEnabled = true
+	b.minBatchSize = 1
+	b.maxBatchSizeBound = 16
+	b.latencySLO = 50 * time.Millisecond
+
+	b.recordLatency(500 * time.Millisecond)
+
+	b.mu.RLock()
+	size := b.maxBatchSize
+	b.mu.RUnlock()
+
+	if size != 3 {
+		t.Fatalf("batch size = %d, want 3 after an SLO breach", size)
+	}
+}
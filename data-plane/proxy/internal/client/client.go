@@ -1,23 +1,32 @@
 package client
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/nexusml/proxy/internal/batcher"
 	"github.com/rs/zerolog/log"
 )
 
-// ModelClient handles communication with the backend Python model server
-type ModelClient struct {
-	baseURL    string
-	httpClient *http.Client
-}
+// Strategy selects which healthy backend handles the next batch.
+type Strategy string
+
+const (
+	StrategyRoundRobin    Strategy = "round_robin"
+	StrategyLeastInFlight Strategy = "least_in_flight"
+	StrategyWeighted      Strategy = "weighted"
+)
 
 // BatchRequest is the payload sent to the model server
 type BatchRequest struct {
@@ -42,10 +51,28 @@ type SingleResponse struct {
 	Error  string          `json:"error,omitempty"`
 }
 
-// New creates a new ModelClient
-func New(baseURL string) *ModelClient {
-	return &ModelClient{
-		baseURL: baseURL,
+// Backend is a single model server replica along with its health and load
+// state. All fields beyond URL and httpClient are mutated under mu.
+type Backend struct {
+	URL        string
+	Weight     int
+	httpClient *http.Client
+
+	mu                  sync.Mutex
+	healthy             bool
+	consecutiveFailures int
+	inFlight            int
+	requestsTotal       int64
+	errorsTotal         int64
+	latencyTotal        time.Duration
+	latencyCount        int64
+}
+
+func newBackend(url string, weight int) *Backend {
+	return &Backend{
+		URL:     url,
+		Weight:  weight,
+		healthy: true,
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 			Transport: &http.Transport{
@@ -57,16 +84,287 @@ func New(baseURL string) *ModelClient {
 	}
 }
 
-// ProcessBatch sends a batch of requests to the model server
-// This function is designed to be used as the batcher.ProcessFunc
-func (c *ModelClient) ProcessBatch(ctx context.Context, batch *batcher.Batch) []batcher.Response {
-	responses := make([]batcher.Response, 0, len(batch.Requests))
+// parseBackendSpec splits a MODEL_SERVER_URLS entry into its URL and weight.
+// Weight is appended as "|<weight>" (e.g. "http://host:8000|3") since the URL
+// itself may already contain a colon for the port. Entries without a weight
+// suffix default to weight 1.
+func parseBackendSpec(spec string) (url string, weight int) {
+	if idx := strings.LastIndex(spec, "|"); idx != -1 {
+		if w, err := strconv.Atoi(spec[idx+1:]); err == nil && w > 0 {
+			return spec[:idx], w
+		}
+	}
+	return spec, 1
+}
+
+func (b *Backend) isHealthy() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.healthy
+}
+
+func (b *Backend) currentInFlight() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.inFlight
+}
+
+func (b *Backend) beginRequest() {
+	b.mu.Lock()
+	b.inFlight++
+	b.requestsTotal++
+	b.mu.Unlock()
+}
+
+func (b *Backend) endRequest(duration time.Duration) {
+	b.mu.Lock()
+	b.inFlight--
+	b.latencyTotal += duration
+	b.latencyCount++
+	b.mu.Unlock()
+}
+
+// recordFailure increments the consecutive-failure count and reports
+// whether the backend just crossed the unhealthy threshold.
+func (b *Backend) recordFailure(maxConsecutiveFailures int) (becameUnhealthy bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.errorsTotal++
+	b.consecutiveFailures++
+	if b.healthy && b.consecutiveFailures >= maxConsecutiveFailures {
+		b.healthy = false
+		return true
+	}
+	return false
+}
+
+func (b *Backend) recordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.consecutiveFailures = 0
+	b.healthy = true
+}
+
+// Stats is a point-in-time snapshot of a backend's health and counters,
+// suitable for rendering on /metrics.
+type Stats struct {
+	URL               string
+	Healthy           bool
+	InFlight          int
+	RequestsTotal     int64
+	ErrorsTotal       int64
+	AvgLatencySeconds float64
+}
+
+func (b *Backend) stats() Stats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var avgLatency float64
+	if b.latencyCount > 0 {
+		avgLatency = b.latencyTotal.Seconds() / float64(b.latencyCount)
+	}
+
+	return Stats{
+		URL:               b.URL,
+		Healthy:           b.healthy,
+		InFlight:          b.inFlight,
+		RequestsTotal:     b.requestsTotal,
+		ErrorsTotal:       b.errorsTotal,
+		AvgLatencySeconds: avgLatency,
+	}
+}
+
+// HealthCheck verifies the backend is reachable
+func (b *Backend) HealthCheck(ctx context.Context) error {
+	url := fmt.Sprintf("%s/health", b.URL)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("backend health check failed with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// retryableError marks a failure as one that should be retried against the
+// next backend (network error, 5xx, or timeout) rather than surfaced as a
+// per-batch error immediately.
+type retryableError struct{ err error }
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+// BackendGroup owns a pool of model server backends and routes batches to a
+// healthy one, retrying on the next backend on failure.
+type BackendGroup struct {
+	name     string
+	backends []*Backend
+	strategy Strategy
+
+	maxConsecutiveFailures int
+	baseBackoff            time.Duration
+	maxBackoff             time.Duration
+
+	mu      sync.Mutex
+	rrIndex int
+}
+
+// NewBackendGroup creates a named BackendGroup from a list of model server
+// URLs. The name is used to identify the group in ServedBy metadata, e.g.
+// "default/http://host:8000". Each entry in urls may carry a weight for
+// StrategyWeighted via a "|<weight>" suffix (e.g. "http://host:8000|3");
+// entries without one default to weight 1.
+func NewBackendGroup(name string, urls []string, strategy Strategy, maxConsecutiveFailures int, baseBackoff, maxBackoff time.Duration) *BackendGroup {
+	backends := make([]*Backend, len(urls))
+	for i, spec := range urls {
+		url, weight := parseBackendSpec(spec)
+		backends[i] = newBackend(url, weight)
+	}
+
+	return &BackendGroup{
+		name:                   name,
+		backends:               backends,
+		strategy:               strategy,
+		maxConsecutiveFailures: maxConsecutiveFailures,
+		baseBackoff:            baseBackoff,
+		maxBackoff:             maxBackoff,
+	}
+}
+
+// Stats returns a snapshot of every backend's health and counters.
+func (g *BackendGroup) Stats() []Stats {
+	stats := make([]Stats, len(g.backends))
+	for i, b := range g.backends {
+		stats[i] = b.stats()
+	}
+	return stats
+}
+
+// ProcessBatch sends a batch of requests to a healthy backend, retrying the
+// whole batch on the next backend when the chosen one fails with a network
+// error, a 5xx, or a timeout. This function is designed to be used as the
+// batcher.ProcessFunc.
+func (g *BackendGroup) ProcessBatch(ctx context.Context, batch *batcher.Batch) []batcher.Response {
+	tried := make(map[*Backend]bool, len(g.backends))
+
+	var lastErr error
+	for attempt := 0; attempt < len(g.backends); attempt++ {
+		backend := g.pickBackend(tried)
+		if backend == nil {
+			break
+		}
+		tried[backend] = true
+
+		responses, err := g.sendToBackend(ctx, backend, batch)
+		if err == nil {
+			return responses
+		}
+
+		lastErr = err
+		if !errors.As(err, new(*retryableError)) {
+			return g.errorResponses(batch, err)
+		}
+
+		log.Warn().Err(err).Str("backend", backend.URL).Msg("Backend request failed, trying next backend")
+	}
+
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no healthy backends available")
+	}
+	return g.errorResponses(batch, lastErr)
+}
+
+// pickBackend chooses the next backend to try, preferring healthy backends
+// that haven't already been tried for this batch, and falling back to any
+// untried backend if none are currently healthy.
+func (g *BackendGroup) pickBackend(excluded map[*Backend]bool) *Backend {
+	candidates := make([]*Backend, 0, len(g.backends))
+	for _, b := range g.backends {
+		if !excluded[b] && b.isHealthy() {
+			candidates = append(candidates, b)
+		}
+	}
+
+	if len(candidates) == 0 {
+		for _, b := range g.backends {
+			if !excluded[b] {
+				candidates = append(candidates, b)
+			}
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	switch g.strategy {
+	case StrategyLeastInFlight:
+		return leastInFlight(candidates)
+	case StrategyWeighted:
+		return weightedPick(candidates)
+	default:
+		return g.roundRobinPick(candidates)
+	}
+}
+
+func (g *BackendGroup) roundRobinPick(candidates []*Backend) *Backend {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	backend := candidates[g.rrIndex%len(candidates)]
+	g.rrIndex++
+	return backend
+}
+
+func leastInFlight(candidates []*Backend) *Backend {
+	best := candidates[0]
+	bestInFlight := best.currentInFlight()
+	for _, b := range candidates[1:] {
+		if inFlight := b.currentInFlight(); inFlight < bestInFlight {
+			best = b
+			bestInFlight = inFlight
+		}
+	}
+	return best
+}
+
+func weightedPick(candidates []*Backend) *Backend {
+	totalWeight := 0
+	for _, b := range candidates {
+		totalWeight += b.Weight
+	}
+	if totalWeight <= 0 {
+		return candidates[0]
+	}
+
+	target := rand.Intn(totalWeight)
+	for _, b := range candidates {
+		target -= b.Weight
+		if target < 0 {
+			return b
+		}
+	}
+	return candidates[len(candidates)-1]
+}
+
+// sendToBackend sends a batch to a specific backend and tracks its health
+// and load counters. The returned error is a *retryableError when the
+// failure is a network error, a timeout, or a 5xx response.
+func (g *BackendGroup) sendToBackend(ctx context.Context, b *Backend, batch *batcher.Batch) ([]batcher.Response, error) {
+	b.beginRequest()
 
-	// Build batch request
 	batchReq := BatchRequest{
 		Requests: make([]SingleRequest, len(batch.Requests)),
 	}
-
 	for i, req := range batch.Requests {
 		batchReq.Requests[i] = SingleRequest{
 			ID:   req.ID,
@@ -74,64 +372,70 @@ func (c *ModelClient) ProcessBatch(ctx context.Context, batch *batcher.Batch) []
 		}
 	}
 
-	// Serialize request
 	reqBody, err := json.Marshal(batchReq)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to marshal batch request")
-		return c.errorResponses(batch, err)
+		b.endRequest(0)
+		return nil, err
 	}
 
-	// Send to model server
-	url := fmt.Sprintf("%s/predict/batch", c.baseURL)
+	url := fmt.Sprintf("%s/predict/batch", b.URL)
 	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(reqBody))
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to create HTTP request")
-		return c.errorResponses(batch, err)
+		b.endRequest(0)
+		return nil, err
 	}
-
 	httpReq.Header.Set("Content-Type", "application/json")
 
 	startTime := time.Now()
-	resp, err := c.httpClient.Do(httpReq)
+	resp, err := b.httpClient.Do(httpReq)
+	duration := time.Since(startTime)
+	b.endRequest(duration)
+
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to send request to model server")
-		return c.errorResponses(batch, err)
+		g.onFailure(b)
+		return nil, &retryableError{err}
 	}
 	defer resp.Body.Close()
 
-	duration := time.Since(startTime)
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		g.onFailure(b)
+		return nil, &retryableError{err}
+	}
+
 	log.Debug().
 		Int("batch_size", len(batch.Requests)).
 		Dur("duration", duration).
 		Int("status_code", resp.StatusCode).
+		Str("backend", b.URL).
 		Msg("Model server response")
 
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to read response body")
-		return c.errorResponses(batch, err)
+	if resp.StatusCode >= 500 {
+		g.onFailure(b)
+		return nil, &retryableError{fmt.Errorf("model server returned status %d: %s", resp.StatusCode, string(body))}
 	}
 
-	// Check HTTP status
 	if resp.StatusCode != http.StatusOK {
-		err := fmt.Errorf("model server returned status %d: %s", resp.StatusCode, string(body))
-		log.Error().Err(err).Msg("Model server error")
-		return c.errorResponses(batch, err)
+		b.recordFailure(g.maxConsecutiveFailures)
+		return nil, fmt.Errorf("model server returned status %d: %s", resp.StatusCode, string(body))
 	}
 
-	// Parse response
 	var batchResp BatchResponse
 	if err := json.Unmarshal(body, &batchResp); err != nil {
-		log.Error().Err(err).Msg("Failed to unmarshal response")
-		return c.errorResponses(batch, err)
+		g.onFailure(b)
+		return nil, &retryableError{err}
 	}
 
-	// Convert to batcher responses
+	b.recordSuccess()
+
+	servedBy := fmt.Sprintf("%s/%s", g.name, b.URL)
+
+	responses := make([]batcher.Response, 0, len(batchResp.Responses))
 	for _, singleResp := range batchResp.Responses {
 		resp := batcher.Response{
-			ID:   singleResp.ID,
-			Data: singleResp.Result,
+			ID:       singleResp.ID,
+			Data:     singleResp.Result,
+			ServedBy: servedBy,
 		}
 		if singleResp.Error != "" {
 			resp.Error = fmt.Errorf("%s", singleResp.Error)
@@ -139,11 +443,43 @@ func (c *ModelClient) ProcessBatch(ctx context.Context, batch *batcher.Batch) []
 		responses = append(responses, resp)
 	}
 
-	return responses
+	return responses, nil
+}
+
+// onFailure records the failure against the backend and, if it just
+// crossed the unhealthy threshold, starts an exponential-backoff probe
+// loop that marks it healthy again once HealthCheck succeeds.
+func (g *BackendGroup) onFailure(b *Backend) {
+	if b.recordFailure(g.maxConsecutiveFailures) {
+		log.Warn().Str("backend", b.URL).Msg("Backend marked unhealthy, starting re-probe")
+		go g.reprobe(b)
+	}
+}
+
+func (g *BackendGroup) reprobe(b *Backend) {
+	backoff := g.baseBackoff
+	for {
+		time.Sleep(backoff)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err := b.HealthCheck(ctx)
+		cancel()
+
+		if err == nil {
+			b.recordSuccess()
+			log.Info().Str("backend", b.URL).Msg("Backend recovered, marking healthy")
+			return
+		}
+
+		backoff *= 2
+		if backoff > g.maxBackoff {
+			backoff = g.maxBackoff
+		}
+	}
 }
 
 // errorResponses creates error responses for all requests in a batch
-func (c *ModelClient) errorResponses(batch *batcher.Batch, err error) []batcher.Response {
+func (g *BackendGroup) errorResponses(batch *batcher.Batch, err error) []batcher.Response {
 	responses := make([]batcher.Response, len(batch.Requests))
 	for i, req := range batch.Requests {
 		responses[i] = batcher.Response{
@@ -154,23 +490,135 @@ func (c *ModelClient) errorResponses(batch *batcher.Batch, err error) []batcher.
 	return responses
 }
 
-// HealthCheck verifies the model server is reachable
-func (c *ModelClient) HealthCheck(ctx context.Context) error {
-	url := fmt.Sprintf("%s/health", c.baseURL)
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+// streamChunkLine is one line of the NDJSON stream the model server sends
+// back from /predict/batch/stream.
+type streamChunkLine struct {
+	ID    string          `json:"id"`
+	Data  json.RawMessage `json:"data,omitempty"`
+	Done  bool            `json:"done,omitempty"`
+	Error string          `json:"error,omitempty"`
+}
+
+// ProcessStream sends a batch of streaming requests to a single backend and
+// demultiplexes the NDJSON response stream back to each request's channel.
+// Unlike ProcessBatch, a failed stream is not retried on another backend:
+// once bytes have started flowing to callers, switching backends mid-stream
+// would produce a corrupt sequence of chunks. This is designed to be used
+// as the batcher.ProcessStreamFunc.
+func (g *BackendGroup) ProcessStream(ctx context.Context, batch *batcher.Batch, chunkChans map[string]chan batcher.StreamChunk) {
+	backend := g.pickBackend(nil)
+	if backend == nil {
+		failAllStreams(chunkChans, fmt.Errorf("no healthy backends available"))
+		return
+	}
+
+	batchReq := BatchRequest{
+		Requests: make([]SingleRequest, len(batch.Requests)),
+	}
+	for i, req := range batch.Requests {
+		batchReq.Requests[i] = SingleRequest{
+			ID:   req.ID,
+			Data: req.Payload,
+		}
+	}
+
+	reqBody, err := json.Marshal(batchReq)
 	if err != nil {
-		return err
+		failAllStreams(chunkChans, err)
+		return
 	}
 
-	resp, err := c.httpClient.Do(req)
+	url := fmt.Sprintf("%s/predict/batch/stream", backend.URL)
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(reqBody))
 	if err != nil {
-		return err
+		failAllStreams(chunkChans, err)
+		return
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/x-ndjson")
+
+	backend.beginRequest()
+	startTime := time.Now()
+
+	resp, err := backend.httpClient.Do(httpReq)
+	if err != nil {
+		backend.endRequest(time.Since(startTime))
+		g.onFailure(backend)
+		failAllStreams(chunkChans, err)
+		return
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("model server health check failed with status %d", resp.StatusCode)
+		backend.endRequest(time.Since(startTime))
+		g.onFailure(backend)
+		body, _ := io.ReadAll(resp.Body)
+		failAllStreams(chunkChans, fmt.Errorf("model server returned status %d: %s", resp.StatusCode, string(body)))
+		return
 	}
 
-	return nil
+	servedBy := fmt.Sprintf("%s/%s", g.name, backend.URL)
+
+	pending := len(chunkChans)
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for pending > 0 && scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var chunk streamChunkLine
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			log.Error().Err(err).Msg("Failed to parse stream chunk")
+			continue
+		}
+
+		ch, ok := chunkChans[chunk.ID]
+		if !ok {
+			continue
+		}
+
+		if chunk.Error != "" {
+			ch <- batcher.StreamChunk{ServedBy: servedBy, Err: fmt.Errorf("%s", chunk.Error)}
+			close(ch)
+			delete(chunkChans, chunk.ID)
+			pending--
+			continue
+		}
+
+		ch <- batcher.StreamChunk{Data: chunk.Data, ServedBy: servedBy}
+
+		if chunk.Done {
+			close(ch)
+			delete(chunkChans, chunk.ID)
+			pending--
+		}
+	}
+
+	backend.endRequest(time.Since(startTime))
+
+	if err := scanner.Err(); err != nil {
+		g.onFailure(backend)
+		failAllStreams(chunkChans, err)
+		return
+	}
+
+	backend.recordSuccess()
+
+	// Anything still pending means the model server closed the stream
+	// before every request reached a "done" or "error" chunk.
+	failAllStreams(chunkChans, fmt.Errorf("model server closed stream before completing all requests"))
+}
+
+// failAllStreams delivers err to every still-open channel in chunkChans and
+// closes it. chunkChans entries are removed as they're drained, so this is
+// a no-op once every request has already completed.
+func failAllStreams(chunkChans map[string]chan batcher.StreamChunk, err error) {
+	for id, ch := range chunkChans {
+		ch <- batcher.StreamChunk{Err: err}
+		close(ch)
+		delete(chunkChans, id)
+	}
 }
@@ -0,0 +1,179 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nexusml/proxy/internal/batcher"
+)
+
+func newTestGroup(strategy Strategy, backends ...*Backend) *BackendGroup {
+	return &BackendGroup{
+		name:                   "test",
+		backends:               backends,
+		strategy:               strategy,
+		maxConsecutiveFailures: 3,
+		baseBackoff:            time.Millisecond,
+		maxBackoff:             10 * time.Millisecond,
+	}
+}
+
+func TestPickBackend_RoundRobinCyclesThroughHealthyBackends(t *testing.T) {
+	a, b2, c := newBackend("http://a", 1), newBackend("http://b", 1), newBackend("http://c", 1)
+	g := newTestGroup(StrategyRoundRobin, a, b2, c)
+
+	var seen []*Backend
+	for i := 0; i < 3; i++ {
+		seen = append(seen, g.pickBackend(nil))
+	}
+
+	if seen[0] != a || seen[1] != b2 || seen[2] != c {
+		t.Fatalf("round robin did not cycle in order: got %v, %v, %v want a, b, c", seen[0].URL, seen[1].URL, seen[2].URL)
+	}
+}
+
+func TestPickBackend_SkipsUnhealthyUnlessNoneAreHealthy(t *testing.T) {
+	a, b2 := newBackend("http://a", 1), newBackend("http://b", 1)
+	a.recordFailure(1) // one failure with threshold 1 marks it unhealthy
+	g := newTestGroup(StrategyRoundRobin, a, b2)
+
+	picked := g.pickBackend(nil)
+	if picked != b2 {
+		t.Fatalf("pickBackend chose %s, want the only healthy backend b", picked.URL)
+	}
+
+	// Once every backend is unhealthy, pickBackend should still return one
+	// rather than nil, so callers can retry and surface a real error.
+	b2.recordFailure(1)
+	if picked := g.pickBackend(nil); picked == nil {
+		t.Fatal("pickBackend returned nil with no healthy backends, want a fallback candidate")
+	}
+}
+
+func TestPickBackend_LeastInFlight(t *testing.T) {
+	a, b2 := newBackend("http://a", 1), newBackend("http://b", 1)
+	a.beginRequest()
+	a.beginRequest()
+	b2.beginRequest()
+	g := newTestGroup(StrategyLeastInFlight, a, b2)
+
+	if picked := g.pickBackend(nil); picked != b2 {
+		t.Fatalf("pickBackend chose %s, want b with fewer in-flight requests", picked.URL)
+	}
+}
+
+func TestWeightedPick_AllZeroWeightFallsBackToFirstCandidate(t *testing.T) {
+	a, b2 := newBackend("http://a", 0), newBackend("http://b", 0)
+	if picked := weightedPick([]*Backend{a, b2}); picked != a {
+		t.Fatalf("weightedPick with zero total weight chose %s, want the first candidate", picked.URL)
+	}
+}
+
+func TestWeightedPick_FavorsHeavierBackend(t *testing.T) {
+	heavy, light := newBackend("http://heavy", 9), newBackend("http://light", 1)
+
+	var heavyPicks int
+	const trials = 2000
+	for i := 0; i < trials; i++ {
+		if weightedPick([]*Backend{heavy, light}) == heavy {
+			heavyPicks++
+		}
+	}
+
+	// Expect roughly 90% with a generous tolerance to avoid a flaky test.
+	if heavyPicks < trials*7/10 {
+		t.Fatalf("heavy backend picked %d/%d times, want it favored by its 9:1 weight", heavyPicks, trials)
+	}
+}
+
+func TestSendToBackend_SuccessMarksHealthyAndSetsServedBy(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"responses":[{"id":"req-1","result":{"ok":true}}]}`))
+	}))
+	defer srv.Close()
+
+	backend := newBackend(srv.URL, 1)
+	g := newTestGroup(StrategyRoundRobin, backend)
+	batch := &batcher.Batch{Requests: []*batcher.Request{{ID: "req-1", Payload: []byte(`{}`)}}}
+
+	responses, err := g.sendToBackend(context.Background(), backend, batch)
+	if err != nil {
+		t.Fatalf("sendToBackend returned error: %v", err)
+	}
+	if len(responses) != 1 || responses[0].ServedBy == "" {
+		t.Fatalf("responses = %+v, want one response with ServedBy set", responses)
+	}
+	if !backend.isHealthy() {
+		t.Fatal("backend should remain healthy after a successful response")
+	}
+}
+
+func TestSendToBackend_ServerErrorIsRetryable(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	backend := newBackend(srv.URL, 1)
+	g := newTestGroup(StrategyRoundRobin, backend)
+	batch := &batcher.Batch{Requests: []*batcher.Request{{ID: "req-1"}}}
+
+	_, err := g.sendToBackend(context.Background(), backend, batch)
+	if !errors.As(err, new(*retryableError)) {
+		t.Fatalf("err = %v, want a *retryableError for a 5xx response", err)
+	}
+}
+
+func TestSendToBackend_ClientErrorIsNotRetryable(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	backend := newBackend(srv.URL, 1)
+	g := newTestGroup(StrategyRoundRobin, backend)
+	batch := &batcher.Batch{Requests: []*batcher.Request{{ID: "req-1"}}}
+
+	_, err := g.sendToBackend(context.Background(), backend, batch)
+	if err == nil || errors.As(err, new(*retryableError)) {
+		t.Fatalf("err = %v, want a non-retryable error for a 4xx response", err)
+	}
+}
+
+func TestOnFailure_MarksUnhealthyAndReprobeRecoversIt(t *testing.T) {
+	healthy := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if healthy {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	backend := newBackend(srv.URL, 1)
+	g := newTestGroup(StrategyRoundRobin, backend)
+	g.maxConsecutiveFailures = 1
+	g.baseBackoff = time.Millisecond
+	g.maxBackoff = 5 * time.Millisecond
+
+	g.onFailure(backend)
+	if backend.isHealthy() {
+		t.Fatal("backend should be unhealthy after crossing maxConsecutiveFailures")
+	}
+
+	// Let the re-probe loop observe a healthy response and recover.
+	healthy = true
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if backend.isHealthy() {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("backend did not recover after the health check started succeeding")
+}
@@ -0,0 +1,62 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nexusml/proxy/internal/batcher"
+)
+
+func TestProcessStream_DemultiplexesChunksByID(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		lines := []string{
+			`{"id":"req-1","data":{"seq":1}}`,
+			`{"id":"req-2","data":{"seq":1}}`,
+			`{"id":"req-1","data":{"seq":2},"done":true}`,
+			`{"id":"req-2","error":"model blew up"}`,
+		}
+		for _, line := range lines {
+			w.Write([]byte(line + "\n"))
+		}
+	}))
+	defer srv.Close()
+
+	g := newTestGroup(StrategyRoundRobin, newBackend(srv.URL, 1))
+	batch := &batcher.Batch{Requests: []*batcher.Request{{ID: "req-1"}, {ID: "req-2"}}}
+	chunkChans := map[string]chan batcher.StreamChunk{
+		"req-1": make(chan batcher.StreamChunk, 4),
+		"req-2": make(chan batcher.StreamChunk, 4),
+	}
+
+	done := make(chan struct{})
+	go func() {
+		g.ProcessStream(context.Background(), batch, chunkChans)
+		close(done)
+	}()
+
+	var req1Chunks []batcher.StreamChunk
+	for chunk := range chunkChans["req-1"] {
+		req1Chunks = append(req1Chunks, chunk)
+	}
+	if len(req1Chunks) != 2 || req1Chunks[1].ServedBy == "" {
+		t.Fatalf("req-1 chunks = %+v, want 2 chunks with ServedBy set", req1Chunks)
+	}
+
+	var req2Chunks []batcher.StreamChunk
+	for chunk := range chunkChans["req-2"] {
+		req2Chunks = append(req2Chunks, chunk)
+	}
+	if len(req2Chunks) != 2 || req2Chunks[0].Err != nil || req2Chunks[1].Err == nil {
+		t.Fatalf("req-2 chunks = %+v, want a data chunk followed by an error chunk", req2Chunks)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("ProcessStream did not return after both requests completed")
+	}
+}
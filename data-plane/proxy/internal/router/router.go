@@ -3,20 +3,26 @@ package router
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"github.com/nexusml/proxy/config"
 	"github.com/nexusml/proxy/internal/batcher"
+	"github.com/nexusml/proxy/internal/client"
 	"github.com/rs/zerolog/log"
 )
 
 // Router handles HTTP routing for the inference proxy
 type Router struct {
-	router  *mux.Router
-	batcher *batcher.Batcher
+	router   *mux.Router
+	batcher  *batcher.Batcher
+	backends *client.BackendGroup
+	cfg      *config.Config
 }
 
 // PredictRequest is the incoming inference request format
@@ -24,13 +30,43 @@ type PredictRequest struct {
 	Data json.RawMessage `json:"data"`
 }
 
-// PredictResponse is the inference response format
+// PredictResponse is the inference response format. Index is only populated
+// for items within a batch response, where it gives the original position of
+// the request within BatchPredictRequest.Requests so a client can tell it
+// apart from an array position introduced by truncation (see
+// BatchPredictResponse.Total).
 type PredictResponse struct {
 	RequestID string          `json:"request_id"`
+	Index     int             `json:"index"`
 	Result    json.RawMessage `json:"result,omitempty"`
+	ServedBy  string          `json:"served_by,omitempty"`
 	Error     string          `json:"error,omitempty"`
 }
 
+// BatchPredictRequest is the incoming batch inference request format.
+// It mirrors the BatchRequest/SingleRequest schema the proxy already uses
+// to talk to the model server internally.
+type BatchPredictRequest struct {
+	Requests []BatchPredictItem `json:"requests"`
+}
+
+// BatchPredictItem represents one inference input within a batch request.
+// ID is optional; if omitted, the server assigns one.
+type BatchPredictItem struct {
+	ID   string          `json:"id,omitempty"`
+	Data json.RawMessage `json:"data"`
+}
+
+// BatchPredictResponse is the response to a POST /predict/batch request.
+// Total is the number of requests submitted in the batch; it lets a client
+// tell a genuinely short batch apart from one whose Responses array was
+// truncated by MaxBatchResponseBytes (responses[last].error will also read
+// "batch response too large" in that case).
+type BatchPredictResponse struct {
+	Total     int               `json:"total"`
+	Responses []PredictResponse `json:"responses"`
+}
+
 // HealthResponse is the health check response format
 type HealthResponse struct {
 	Status        string  `json:"status"`
@@ -41,10 +77,12 @@ type HealthResponse struct {
 }
 
 // New creates a new Router
-func New(b *batcher.Batcher) *Router {
+func New(b *batcher.Batcher, backends *client.BackendGroup, cfg *config.Config) *Router {
 	r := &Router{
-		router:  mux.NewRouter(),
-		batcher: b,
+		router:   mux.NewRouter(),
+		batcher:  b,
+		backends: backends,
+		cfg:      cfg,
 	}
 	r.setupRoutes()
 	return r
@@ -62,6 +100,12 @@ func (r *Router) setupRoutes() {
 	// Prediction endpoint - single request (gets batched internally)
 	r.router.HandleFunc("/predict", r.predictHandler).Methods("POST")
 
+	// Prediction endpoint - client-side batch of requests in one call
+	r.router.HandleFunc("/predict/batch", r.predictBatchHandler).Methods("POST")
+
+	// Prediction endpoint - single request, streamed back as SSE chunks
+	r.router.HandleFunc("/predict/stream", r.predictStreamHandler).Methods("POST")
+
 	// Ready check (for Kubernetes)
 	r.router.HandleFunc("/ready", r.readyHandler).Methods("GET")
 
@@ -106,6 +150,42 @@ func (r *Router) metricsHandler(w http.ResponseWriter, req *http.Request) {
 	w.Write([]byte("# HELP nexus_proxy_batch_size_avg Average batch size\n"))
 	w.Write([]byte("# TYPE nexus_proxy_batch_size_avg gauge\n"))
 	w.Write([]byte("nexus_proxy_batch_size_avg " + formatFloat(avgBatchSize) + "\n"))
+
+	currentBatchSize, currentTimeout, batchLatency := r.batcher.AdaptiveMetrics()
+
+	w.Write([]byte("# HELP nexus_proxy_current_batch_size Batch size currently in effect\n"))
+	w.Write([]byte("# TYPE nexus_proxy_current_batch_size gauge\n"))
+	w.Write([]byte("nexus_proxy_current_batch_size " + formatInt(int64(currentBatchSize)) + "\n"))
+
+	w.Write([]byte("# HELP nexus_proxy_current_batch_timeout_ms Batch collection timeout currently in effect\n"))
+	w.Write([]byte("# TYPE nexus_proxy_current_batch_timeout_ms gauge\n"))
+	w.Write([]byte("nexus_proxy_current_batch_timeout_ms " + formatInt(currentTimeout.Milliseconds()) + "\n"))
+
+	w.Write([]byte("# HELP nexus_proxy_batch_latency_seconds EWMA of batch processing latency\n"))
+	w.Write([]byte("# TYPE nexus_proxy_batch_latency_seconds gauge\n"))
+	w.Write([]byte("nexus_proxy_batch_latency_seconds " + formatFloat(batchLatency) + "\n"))
+
+	if r.backends == nil {
+		return
+	}
+
+	w.Write([]byte("# HELP nexus_proxy_backend_requests_total Total requests sent to a backend\n"))
+	w.Write([]byte("# TYPE nexus_proxy_backend_requests_total counter\n"))
+	for _, s := range r.backends.Stats() {
+		w.Write([]byte(fmt.Sprintf("nexus_proxy_backend_requests_total{backend=%q} %s\n", s.URL, formatInt(s.RequestsTotal))))
+	}
+
+	w.Write([]byte("# HELP nexus_proxy_backend_errors_total Total errors from a backend\n"))
+	w.Write([]byte("# TYPE nexus_proxy_backend_errors_total counter\n"))
+	for _, s := range r.backends.Stats() {
+		w.Write([]byte(fmt.Sprintf("nexus_proxy_backend_errors_total{backend=%q} %s\n", s.URL, formatInt(s.ErrorsTotal))))
+	}
+
+	w.Write([]byte("# HELP nexus_proxy_backend_latency_seconds Average backend response latency\n"))
+	w.Write([]byte("# TYPE nexus_proxy_backend_latency_seconds gauge\n"))
+	for _, s := range r.backends.Stats() {
+		w.Write([]byte(fmt.Sprintf("nexus_proxy_backend_latency_seconds{backend=%q} %s\n", s.URL, formatFloat(s.AvgLatencySeconds))))
+	}
 }
 
 func (r *Router) readyHandler(w http.ResponseWriter, req *http.Request) {
@@ -130,11 +210,16 @@ func (r *Router) predictHandler(w http.ResponseWriter, req *http.Request) {
 	defer cancel()
 
 	// Submit to batcher and wait for response
-	result, err := r.batcher.Submit(ctx, requestID, body)
+	result, servedBy, err := r.batcher.Submit(ctx, requestID, body)
 
 	// Build response
 	resp := PredictResponse{
 		RequestID: requestID,
+		ServedBy:  servedBy,
+	}
+
+	if servedBy != "" {
+		w.Header().Set("X-Served-By", servedBy)
 	}
 
 	if err != nil {
@@ -151,6 +236,183 @@ func (r *Router) predictHandler(w http.ResponseWriter, req *http.Request) {
 	json.NewEncoder(w).Encode(resp)
 }
 
+// predictStreamHandler submits a single request for streaming inference and
+// relays the incremental chunks to the client as Server-Sent Events, with
+// event types "chunk", "done", and "error".
+func (r *Router) predictStreamHandler(w http.ResponseWriter, req *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		sendError(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		sendError(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+	defer req.Body.Close()
+
+	requestID := uuid.New().String()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	chunks, err := r.batcher.SubmitStreaming(req.Context(), requestID, body)
+	if err != nil {
+		writeSSE(w, flusher, "error", requestID, "", nil, err.Error())
+		return
+	}
+
+	var servedBy string
+	for chunk := range chunks {
+		if chunk.ServedBy != "" {
+			servedBy = chunk.ServedBy
+		}
+		if chunk.Err != nil {
+			writeSSE(w, flusher, "error", requestID, servedBy, nil, chunk.Err.Error())
+			return
+		}
+		writeSSE(w, flusher, "chunk", requestID, servedBy, chunk.Data, "")
+	}
+
+	// The stream's headers were already flushed before the backend was
+	// chosen, so X-Served-By can't reach the client as a header for this
+	// endpoint. Set it anyway so loggingMiddleware's post-request read of
+	// the header map still logs served_by, consistent with /predict.
+	if servedBy != "" {
+		w.Header().Set("X-Served-By", servedBy)
+	}
+
+	writeSSE(w, flusher, "done", requestID, servedBy, nil, "")
+}
+
+// writeSSE writes one Server-Sent Event frame carrying a PredictResponse
+// payload and flushes it immediately.
+func writeSSE(w http.ResponseWriter, flusher http.Flusher, event, requestID, servedBy string, data json.RawMessage, errMsg string) {
+	payload := PredictResponse{RequestID: requestID, ServedBy: servedBy}
+	if errMsg != "" {
+		payload.Error = errMsg
+	} else {
+		payload.Result = data
+	}
+
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		encoded, _ = json.Marshal(PredictResponse{RequestID: requestID, Error: err.Error()})
+	}
+
+	fmt.Fprintf(w, "event: %s\n", event)
+	fmt.Fprintf(w, "data: %s\n\n", encoded)
+	flusher.Flush()
+}
+
+// predictBatchHandler accepts an array of prediction inputs in a single HTTP
+// request, fans them out to the batcher, and streams back an ordered array
+// of results. Per-item failures are reported inline so a client can retry
+// only the failed sub-requests instead of the whole batch.
+func (r *Router) predictBatchHandler(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		sendError(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+	defer req.Body.Close()
+
+	var batchReq BatchPredictRequest
+	if err := json.Unmarshal(body, &batchReq); err != nil {
+		sendError(w, "Invalid batch request body", http.StatusBadRequest)
+		return
+	}
+
+	if len(batchReq.Requests) == 0 {
+		sendError(w, "Batch must contain at least one request", http.StatusBadRequest)
+		return
+	}
+
+	if len(batchReq.Requests) > r.cfg.MaxBatchRequestItems {
+		sendError(w, fmt.Sprintf("batch contains %d items, exceeds limit of %d", len(batchReq.Requests), r.cfg.MaxBatchRequestItems), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), 30*time.Second)
+	defer cancel()
+
+	results := make([]PredictResponse, len(batchReq.Requests))
+
+	var wg sync.WaitGroup
+	for i, item := range batchReq.Requests {
+		requestID := item.ID
+		if requestID == "" {
+			requestID = uuid.New().String()
+		}
+
+		wg.Add(1)
+		go func(i int, requestID string, data json.RawMessage) {
+			defer wg.Done()
+
+			result, servedBy, err := r.batcher.Submit(ctx, requestID, data)
+
+			resp := PredictResponse{RequestID: requestID, Index: i, ServedBy: servedBy}
+			if err != nil {
+				resp.Error = err.Error()
+			} else {
+				resp.Result = result
+			}
+			results[i] = resp
+		}(i, requestID, item.Data)
+	}
+	wg.Wait()
+
+	r.writeBatchResponse(w, results)
+}
+
+// writeBatchResponse streams the ordered batch results back to the client,
+// stopping and emitting a single "batch response too large" error entry
+// once the accumulated response size would exceed MaxBatchResponseBytes.
+func (r *Router) writeBatchResponse(w http.ResponseWriter, results []PredictResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"total":%d,"responses":[`, len(results))
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		log.Warn().Msg("ResponseWriter does not support flushing; batch responses will be buffered instead of streamed")
+	}
+	written := 0
+
+	for i, resp := range results {
+		encoded, err := json.Marshal(resp)
+		if err != nil {
+			encoded, _ = json.Marshal(PredictResponse{RequestID: resp.RequestID, Index: resp.Index, Error: err.Error()})
+		}
+
+		sep := ""
+		if i > 0 {
+			sep = ","
+		}
+
+		if written+len(sep)+len(encoded) > r.cfg.MaxBatchResponseBytes {
+			tooLarge, _ := json.Marshal(PredictResponse{RequestID: resp.RequestID, Index: resp.Index, Error: "batch response too large"})
+			w.Write([]byte(sep))
+			w.Write(tooLarge)
+			break
+		}
+
+		w.Write([]byte(sep))
+		w.Write(encoded)
+		written += len(sep) + len(encoded)
+
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	w.Write([]byte(`]}`))
+}
+
 func sendError(w http.ResponseWriter, message string, status int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -182,6 +444,7 @@ func loggingMiddleware(next http.Handler) http.Handler {
 			Str("method", r.Method).
 			Str("path", r.URL.Path).
 			Int("status", wrapped.statusCode).
+			Str("served_by", wrapped.Header().Get("X-Served-By")).
 			Dur("duration", time.Since(start)).
 			Msg("Request completed")
 	})
@@ -208,3 +471,14 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.statusCode = code
 	rw.ResponseWriter.WriteHeader(code)
 }
+
+// Flush lets responseWriter satisfy http.Flusher when the underlying
+// ResponseWriter does. Without this, the embedded field's static type
+// (http.ResponseWriter) hides the real writer's Flush method from Go's
+// method-set promotion, and handlers behind loggingMiddleware would never
+// see a usable http.Flusher even though the real writer supports one.
+func (rw *responseWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}